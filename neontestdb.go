@@ -3,25 +3,136 @@ package neontestdb
 import (
 	"bytes"
 	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Client struct {
-	Client    http.Client
-	Key       string
-	ProjectID string
-	Branch    string
-	NoCleanup bool
+	Client      http.Client
+	Key         string
+	ProjectID   string
+	Branch      string
+	NoCleanup   bool
+	RetryPolicy RetryPolicy
+	// OperationPollPolicy governs polling for operation status
+	// (WaitForOperations) and endpoint readiness (probeConnection).
+	// It is intentionally distinct from RetryPolicy, which governs
+	// individual HTTP request retries: branch/endpoint readiness can
+	// take much longer than a single HTTP call should ever be retried
+	// for. Defaults to DefaultOperationPollPolicy when unset.
+	OperationPollPolicy RetryPolicy
+	// SkipWaitForReady, when true, makes CreateBranch and DeleteBranch
+	// return as soon as the API call succeeds instead of blocking until
+	// the branch's async operations reach "finished" (and, for
+	// CreateBranch, until the new endpoint accepts a connection).
+	// Waiting is on by default for every Client, including ones built by
+	// hand, the same way NoCleanup's zero value keeps cleanup enabled.
+	SkipWaitForReady bool
+	// EndpointDefaults shapes the endpoint created alongside a new
+	// branch (pooler, autoscaling limits, region, suspend timeout, ...).
+	// A per-call WithEndpoint option overrides it; an empty Type always
+	// falls back to "read_write".
+	EndpointDefaults CreateEndpoint
+}
+
+// RetryPolicy decides whether a request should be retried and how long
+// to wait before the next attempt. attempt is 1 on the first try, resp
+// is nil on a transport error and err is nil on a non-nil resp.
+type RetryPolicy interface {
+	Decide(attempt int, elapsed time.Duration, resp *http.Response, err error) (retry bool, sleep time.Duration)
+}
+
+// ExponentialBackoff is the default RetryPolicy: it doubles the delay on
+// each attempt, caps it at MaxDelay, and adds jitter so concurrent
+// callers don't retry in lockstep. Source may be set to a deterministic
+// rand.Source in tests; it defaults to the global rand source.
+type ExponentialBackoff struct {
+	MaxAttempts     int
+	MaxElapsed      time.Duration
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	RetryableStatus []int
+	Source          rand.Source
+}
+
+// DefaultRetryPolicy returns the ExponentialBackoff used for HTTP
+// requests by a Client whose RetryPolicy field is left unset.
+// MaxAttempts is set high enough that MaxElapsed, not MaxAttempts, is
+// what actually bounds a retry sequence at these delay settings.
+func DefaultRetryPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts:     20,
+		MaxElapsed:      30 * time.Second,
+		BaseDelay:       50 * time.Millisecond,
+		MaxDelay:        5 * time.Second,
+		RetryableStatus: []int{http.StatusLocked, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// DefaultOperationPollPolicy returns the ExponentialBackoff used to poll
+// operation status and endpoint readiness by a Client whose
+// OperationPollPolicy field is left unset. It allows a much longer
+// MaxElapsed than DefaultRetryPolicy: branch creation and endpoint
+// cold-starts routinely take longer than a single HTTP call should ever
+// be retried for, so the two can't share one policy's budget.
+func DefaultOperationPollPolicy() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts: 120,
+		MaxElapsed:  5 * time.Minute,
+		BaseDelay:   250 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+}
+
+func (p *ExponentialBackoff) Decide(attempt int, elapsed time.Duration, resp *http.Response, err error) (bool, time.Duration) {
+	if attempt >= p.MaxAttempts {
+		return false, 0
+	}
+	if p.MaxElapsed > 0 && elapsed >= p.MaxElapsed {
+		return false, 0
+	}
+	if err == nil && resp != nil && !slices.Contains(p.RetryableStatus, resp.StatusCode) {
+		return false, 0
+	}
+
+	// Cap the shift distance itself rather than clamping the result: past
+	// ~63 bits 1<<uint(attempt-1) overflows int64 and can go negative,
+	// which would slip by the delay > p.MaxDelay check below (a negative
+	// number is never greater than a positive MaxDelay).
+	shift := attempt - 1
+	if shift > 62 {
+		shift = 62
+	}
+	delay := p.BaseDelay * time.Duration(int64(1)<<uint(shift))
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+
+	max := int64(delay/2) + 1
+	var jitter int64
+	if p.Source != nil {
+		jitter = rand.New(p.Source).Int63n(max)
+	} else {
+		jitter = rand.Int63n(max)
+	}
+	return true, delay/2 + time.Duration(jitter)
 }
 
 var defaultBranch = "main"
@@ -30,174 +141,852 @@ func SetDefaultBranch(branch string) {
 	defaultBranch = branch
 }
 
-func LoadClient() Client {
-	require := func(key string) string {
+// LoadClient builds a Client from the NEON_API_KEY and NEON_PROJECT_ID
+// environment variables. It returns an error rather than exiting the
+// process so callers can decide how to handle a missing configuration;
+// see MustLoadClient for the previous exit-on-error behavior.
+func LoadClient() (Client, error) {
+	require := func(key string) (string, error) {
 		value := os.Getenv(key)
 		if value == "" {
-			log.Fatalf("missing required environment variable: %s", key)
+			return "", fmt.Errorf("missing required environment variable: %s", key)
 		}
-		return value
+		return value, nil
+	}
+	apiKey, err := require("NEON_API_KEY")
+	if err != nil {
+		return Client{}, err
+	}
+	projectID, err := require("NEON_PROJECT_ID")
+	if err != nil {
+		return Client{}, err
 	}
 	return Client{
 		Client:    http.Client{},
-		Key:       require("NEON_API_KEY"),
-		ProjectID: require("NEON_PROJECT_ID"),
+		Key:       apiKey,
+		ProjectID: projectID,
 		Branch:    defaultBranch,
+	}, nil
+}
+
+// MustLoadClient is a thin wrapper around LoadClient for callers that
+// prefer to fail fast on misconfiguration instead of handling the error.
+func MustLoadClient() Client {
+	client, err := LoadClient()
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
+	return client
 }
 
-func (n Client) UsingTestBranch(t *testing.T, do func(ConnectionURI)) {
+// TestBranch creates a branch named for the running test, with a short
+// random suffix so t.Parallel() subtests sharing a hostname and test
+// name never collide, and registers its deletion via t.Cleanup so it
+// runs even after t.Fatal, t.Skip, or a panic. If n.NoCleanup is set,
+// the branch is left running and its name and connection URI are
+// logged via t.Logf so a developer can attach to it for post-mortem.
+func (n Client) TestBranch(t *testing.T) ConnectionURI {
+	return n.TestBranchContext(t.Context(), t)
+}
+
+func (n Client) TestBranchContext(ctx context.Context, t *testing.T) ConnectionURI {
 	hostname, _ := os.Hostname()
-	branch := strings.ReplaceAll(fmt.Sprintf("%s.%s", hostname, t.Name()), "/", ".")
-	n.UsingBranch(branch, do)
+	branch := strings.ReplaceAll(fmt.Sprintf("%s.%s.%s", hostname, t.Name(), randomSuffix(4)), "/", ".")
+
+	created, err := n.CreateBranchContext(ctx, branch)
+	if err != nil {
+		t.Fatalf("error creating branch %s: %v", branch, err)
+	}
+
+	uri := poolerConnectionURI(created)
+
+	if n.NoCleanup {
+		t.Logf("branch %s (id %s) left running at %s (NoCleanup set)", branch, created.Branch.ID, uri.ConnectionURI)
+	} else {
+		t.Cleanup(func() {
+			if err := n.DeleteBranchContext(context.Background(), created.Branch.ID); err != nil {
+				t.Logf("error deleting branch %s: %v", created.Branch.ID, err)
+			}
+		})
+	}
+
+	return uri
+}
+
+// UsingTestBranch is a thin closure-based wrapper around TestBranch,
+// kept for callers that prefer passing a callback over reading the
+// ConnectionURI directly from the test body.
+func (n Client) UsingTestBranch(t *testing.T, do func(ConnectionURI)) {
+	n.UsingTestBranchContext(t.Context(), t, do)
+}
+
+func (n Client) UsingTestBranchContext(ctx context.Context, t *testing.T, do func(ConnectionURI)) {
+	do(n.TestBranchContext(ctx, t))
+}
+
+func (n Client) UsingBranch(t *testing.T, name string, do func(ConnectionURI)) {
+	n.UsingBranchContext(t.Context(), t, name, do)
+}
+
+func (n Client) UsingBranchContext(ctx context.Context, t *testing.T, name string, do func(ConnectionURI)) {
+	created, err := n.ForcedCreateBranchContext(ctx, name)
+	if err != nil {
+		t.Fatalf("error creating branch %s: %v", name, err)
+	}
+	do(poolerConnectionURI(created))
+	if !n.NoCleanup {
+		if err := n.DeleteBranchContext(ctx, created.Branch.ID); err != nil {
+			t.Fatalf("error deleting branch %s: %v", created.Branch.ID, err)
+		}
+	}
+}
+
+// poolerConnectionURI returns created's primary ConnectionURI rewritten
+// to point at the pooler host whenever the response's
+// ConnectionParameters carry a PoolerHost, so tests exercise the same
+// connection path as production instead of bypassing the pooler. The
+// endpoints' PoolerEnabled flag is not consulted.
+func poolerConnectionURI(created *BranchCreated) ConnectionURI {
+	uri := created.ConnectionURIs[0]
+	poolerHost := uri.ConnectionParameters.PoolerHost
+	if poolerHost == "" || uri.ConnectionParameters.Host == "" {
+		return uri
+	}
+	uri.ConnectionURI = strings.Replace(uri.ConnectionURI, uri.ConnectionParameters.Host, poolerHost, 1)
+	uri.ConnectionParameters.Host = poolerHost
+	return uri
+}
+
+// UsingBranchAt is the LSN-anchored counterpart to UsingBranch: it
+// creates the branch as a point-in-time copy of n.Branch at lsn so a
+// test can reproduce a known-bad database state by replaying against a
+// committed LSN instead of the parent's current tip.
+func (n Client) UsingBranchAt(t *testing.T, name, lsn string, do func(ConnectionURI)) {
+	n.UsingBranchAtContext(t.Context(), t, name, lsn, do)
 }
 
-func (n Client) UsingBranch(name string, do func(ConnectionURI)) {
-	created := n.ForcedCreateBranch(name)
-	do(created.ConnectionURIs[0])
+func (n Client) UsingBranchAtContext(ctx context.Context, t *testing.T, name, lsn string, do func(ConnectionURI)) {
+	parent, err := n.GetBranchByNameContext(ctx, n.Branch)
+	if err != nil {
+		t.Fatalf("error looking up parent branch %s: %v", n.Branch, err)
+	}
+	if parent == nil {
+		t.Fatalf("parent branch '%s' not found", n.Branch)
+	}
+
+	created, err := n.ForcedCreateBranchAtLSNContext(ctx, name, parent.ID, lsn)
+	if err != nil {
+		t.Fatalf("error creating branch %s at lsn %s: %v", name, lsn, err)
+	}
+	do(poolerConnectionURI(created))
 	if !n.NoCleanup {
-		n.DeleteBranch(created.Branch.ID)
+		if err := n.DeleteBranchContext(ctx, created.Branch.ID); err != nil {
+			t.Fatalf("error deleting branch %s: %v", created.Branch.ID, err)
+		}
 	}
 }
 
-func (n Client) GetBranches() *Branches {
-	resp := n.Do(n.NewRequest("GET", n.BranchesURL(), nil))
+func (n Client) ForcedCreateBranchAtLSNContext(ctx context.Context, name, parentID, lsn string) (*BranchCreated, error) {
+	branch, err := n.GetBranchContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if branch != nil {
+		if err := n.DeleteBranchContext(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return n.CreateBranchAtLSNContext(ctx, name, parentID, lsn)
+}
+
+func (n Client) GetBranches() (*Branches, error) {
+	return n.GetBranchesContext(context.Background())
+}
+
+func (n Client) GetBranchesContext(ctx context.Context) (*Branches, error) {
+	req, err := n.NewRequestContext(ctx, "GET", n.BranchesURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil
+		return nil, nil
 	}
 
-	validateStatus(resp, http.StatusOK)
+	if err := validateStatus(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
 
 	return parseResponse[Branches](resp)
 }
 
-func (n Client) ForcedCreateBranch(name string) *BranchCreated {
-	if branch := n.GetBranch(name); branch != nil {
-		n.DeleteBranch(name)
+func (n Client) ForcedCreateBranch(name string) (*BranchCreated, error) {
+	return n.ForcedCreateBranchContext(context.Background(), name)
+}
+
+func (n Client) ForcedCreateBranchContext(ctx context.Context, name string) (*BranchCreated, error) {
+	branch, err := n.GetBranchContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if branch != nil {
+		if err := n.DeleteBranchContext(ctx, name); err != nil {
+			return nil, err
+		}
 	}
-	return n.CreateBranch(name)
+	return n.CreateBranchContext(ctx, name)
+}
+
+func (n Client) GetBranch(name string) (*Branch, error) {
+	return n.GetBranchContext(context.Background(), name)
 }
 
-func (n Client) GetBranch(name string) *Branch {
-	resp := n.Do(n.NewRequest("GET", n.BranchURL(name), nil))
+func (n Client) GetBranchContext(ctx context.Context, name string) (*Branch, error) {
+	req, err := n.NewRequestContext(ctx, "GET", n.BranchURL(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil
+		return nil, nil
 	}
 
-	validateStatus(resp, http.StatusOK)
+	if err := validateStatus(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
 
 	return parseResponse[Branch](resp)
 }
 
-func (n Client) GetBranchByName(name string) *Branch {
-	for _, branch := range n.GetBranches().Branches {
+func (n Client) GetBranchByName(name string) (*Branch, error) {
+	return n.GetBranchByNameContext(context.Background(), name)
+}
+
+func (n Client) GetBranchByNameContext(ctx context.Context, name string) (*Branch, error) {
+	branches, err := n.GetBranchesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, branch := range branches.Branches {
 		if branch.Name == name {
-			return &branch
+			return &branch, nil
 		}
 	}
-	return nil
+	return nil, nil
+}
+
+func (n Client) CreateBranch(name string, opts ...CreateBranchOption) (*BranchCreated, error) {
+	return n.CreateBranchContext(context.Background(), name, opts...)
 }
 
-func (n Client) CreateBranch(name string) *BranchCreated {
-	parent := n.GetBranchByName(n.Branch)
+func (n Client) CreateBranchContext(ctx context.Context, name string, opts ...CreateBranchOption) (*BranchCreated, error) {
+	parent, err := n.GetBranchByNameContext(ctx, n.Branch)
+	if err != nil {
+		return nil, err
+	}
 	if parent == nil {
-		log.Fatalf("error creating branch %s, parent branch '%s' not found", name, n.Branch)
+		return nil, fmt.Errorf("error creating branch %s, parent branch '%s' not found", name, n.Branch)
 	}
 
-	start := time.Now()
-	for retry := 10 * time.Millisecond; retry <= 100*time.Millisecond; retry += 10 {
-		create := CreateBranch{
-			Name:     name,
-			ParentID: parent.ID,
-		}
-		resp := n.Do(n.NewCreateBranchRequest(create))
-		defer resp.Body.Close()
+	return n.createBranch(ctx, CreateBranch{Name: name, ParentID: parent.ID}, opts...)
+}
+
+// CreateBranchAtLSN creates a branch anchored at a specific LSN on the
+// given parent, rather than the tip of n.Branch, so tests can replay
+// against a known, committed point in the parent's history.
+func (n Client) CreateBranchAtLSN(name, parentID, lsn string, opts ...CreateBranchOption) (*BranchCreated, error) {
+	return n.CreateBranchAtLSNContext(context.Background(), name, parentID, lsn, opts...)
+}
+
+func (n Client) CreateBranchAtLSNContext(ctx context.Context, name, parentID, lsn string, opts ...CreateBranchOption) (*BranchCreated, error) {
+	return n.createBranch(ctx, CreateBranch{Name: name, ParentID: parentID, ParentLSN: lsn}, opts...)
+}
 
-		if resp.StatusCode == http.StatusLocked {
-			time.Sleep(retry)
-			continue
+// CreateBranchAtTime creates a branch anchored at the parent's state as
+// of a specific wall-clock time.
+func (n Client) CreateBranchAtTime(name, parentID string, ts time.Time, opts ...CreateBranchOption) (*BranchCreated, error) {
+	return n.CreateBranchAtTimeContext(context.Background(), name, parentID, ts, opts...)
+}
+
+func (n Client) CreateBranchAtTimeContext(ctx context.Context, name, parentID string, ts time.Time, opts ...CreateBranchOption) (*BranchCreated, error) {
+	return n.createBranch(ctx, CreateBranch{Name: name, ParentID: parentID, ParentTimestamp: &ts}, opts...)
+}
+
+// CreateBranchOption customizes a single CreateBranch call, such as the
+// shape of its endpoint. See WithEndpoint.
+type CreateBranchOption func(*createBranchOptions)
+
+type createBranchOptions struct {
+	endpoint *CreateEndpoint
+}
+
+// WithEndpoint overrides the Client's EndpointDefaults for a single
+// CreateBranch call, e.g. to request a pooled, autoscaling, or
+// region-pinned endpoint instead of the default read_write one.
+func WithEndpoint(endpoint CreateEndpoint) CreateBranchOption {
+	return func(o *createBranchOptions) {
+		o.endpoint = &endpoint
+	}
+}
+
+func (n Client) createBranch(ctx context.Context, create CreateBranch, opts ...CreateBranchOption) (*BranchCreated, error) {
+	var options createBranchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	endpoint := n.EndpointDefaults
+	if options.endpoint != nil {
+		endpoint = *options.endpoint
+	}
+	if endpoint.Type == "" {
+		endpoint.Type = "read_write"
+	}
+
+	req, err := n.NewCreateBranchRequestContext(ctx, create, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Do retries StatusLocked (and other transient statuses) internally
+	// via the Client's RetryPolicy, so a single call here is sufficient.
+	resp, err := n.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create branch %s: %w", create.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if err := validateStatus(resp, http.StatusOK, http.StatusCreated); err != nil {
+		return nil, err
+	}
+
+	created, err := parseResponse[BranchCreated](resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.SkipWaitForReady {
+		if err := n.WaitForOperations(ctx, created.Operations); err != nil {
+			return nil, fmt.Errorf("branch %s: %w", create.Name, err)
 		}
+		if len(created.ConnectionURIs) > 0 {
+			if err := n.probeConnection(ctx, created.ConnectionURIs[0].ConnectionURI); err != nil {
+				return nil, fmt.Errorf("branch %s: %w", create.Name, err)
+			}
+		}
+	}
 
-		validateStatus(resp, http.StatusOK, http.StatusCreated)
+	return created, nil
+}
 
-		return parseResponse[BranchCreated](resp)
+// probeConnection waits for a freshly created endpoint to accept
+// connections, retrying with the Client's OperationPollPolicy so cold-start
+// latency doesn't surface as a flaky pgx.Connect failure in callers.
+func (n Client) probeConnection(ctx context.Context, uri string) error {
+	policy := n.OperationPollPolicy
+	if policy == nil {
+		policy = DefaultOperationPollPolicy()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		pingErr := func() error {
+			conn, err := pgx.Connect(ctx, uri)
+			if err != nil {
+				return err
+			}
+			defer conn.Close(ctx)
+			return conn.Ping(ctx)
+		}()
+		if pingErr == nil {
+			return nil
+		}
+
+		retry, sleep := policy.Decide(attempt, time.Since(start), nil, pingErr)
+		if !retry {
+			return fmt.Errorf("endpoint did not become ready: %w", pingErr)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
 	}
-	log.Fatalf("failed to create branch after: %v", time.Since(start))
-	return nil
 }
 
-func (n Client) NewCreateBranchRequest(branch CreateBranch) *http.Request {
+func (n Client) NewCreateBranchRequest(branch CreateBranch, endpoint CreateEndpoint) (*http.Request, error) {
+	return n.NewCreateBranchRequestContext(context.Background(), branch, endpoint)
+}
+
+func (n Client) NewCreateBranchRequestContext(ctx context.Context, branch CreateBranch, endpoint CreateEndpoint) (*http.Request, error) {
 	create := CreateBranchRequest{
 		Endpoints: []CreateEndpoint{
-			{Type: "read_write"},
+			endpoint,
 		},
 		Branch: branch,
 	}
 	body, err := json.Marshal(create)
 	if err != nil {
-		log.Fatalf("error marshaling request for %v %v", branch, err)
+		return nil, fmt.Errorf("error marshaling request for %v: %w", branch, err)
+	}
+	req, err := n.NewRequestContext(ctx, "POST", n.BranchesURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
 	}
-	req := n.NewRequest("POST", n.BranchesURL(), bytes.NewReader(body))
 	req.Header.Add("Content-Type", "application/json")
-	return req
+	return req, nil
 }
 
 func (n Client) BranchesURL() string {
 	return fmt.Sprintf("https://console.neon.tech/api/v2/projects/%s/branches", n.ProjectID)
 }
 
-func (n Client) DeleteBranch(name string) {
-	resp := n.Do(n.NewRequest("DELETE", n.BranchURL(name), nil))
+func (n Client) DeleteBranch(name string) error {
+	return n.DeleteBranchContext(context.Background(), name)
+}
+
+func (n Client) DeleteBranchContext(ctx context.Context, name string) error {
+	req, err := n.NewRequestContext(ctx, "DELETE", n.BranchURL(name), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := n.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := validateStatus(resp, http.StatusOK); err != nil {
+		return err
+	}
+
+	if n.SkipWaitForReady {
+		return nil
+	}
+
+	deleted, err := parseResponse[BranchDeleted](resp)
+	if err != nil {
+		return err
+	}
+	return n.WaitForOperations(ctx, deleted.Operations)
+}
+
+// WaitForOperations blocks until every operation in ops reaches the
+// "finished" status, polling GetOperationContext with the Client's
+// OperationPollPolicy. It returns an error as soon as any operation ends in
+// "failed", "error", or "cancelled", or once the policy gives up.
+func (n Client) WaitForOperations(ctx context.Context, ops []Operation) error {
+	for _, op := range ops {
+		if err := n.waitForOperation(ctx, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n Client) waitForOperation(ctx context.Context, op Operation) error {
+	policy := n.OperationPollPolicy
+	if policy == nil {
+		policy = DefaultOperationPollPolicy()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		current, err := n.GetOperationContext(ctx, op.ID)
+		if err != nil {
+			return err
+		}
+
+		switch current.Status {
+		case "finished":
+			return nil
+		case "failed", "error", "cancelled":
+			return fmt.Errorf("operation %s (%s) on branch %s ended with status %s", current.ID, current.Action, current.BranchID, current.Status)
+		}
+
+		retry, sleep := policy.Decide(attempt, time.Since(start), nil, nil)
+		if !retry {
+			return fmt.Errorf("timed out waiting for operation %s (%s), last status %s", current.ID, current.Action, current.Status)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for operation %s: %w", current.ID, ctx.Err())
+		case <-time.After(sleep):
+		}
+	}
+}
+
+func (n Client) GetOperation(id string) (*Operation, error) {
+	return n.GetOperationContext(context.Background(), id)
+}
+
+func (n Client) GetOperationContext(ctx context.Context, id string) (*Operation, error) {
+	req, err := n.NewRequestContext(ctx, "GET", n.OperationURL(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
-	validateStatus(resp, http.StatusOK)
+	if err := validateStatus(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	result, err := parseResponse[OperationResponse](resp)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Operation, nil
+}
+
+func (n Client) OperationURL(operationID string) string {
+	return fmt.Sprintf("https://console.neon.tech/api/v2/projects/%s/operations/%s", n.ProjectID, operationID)
+}
+
+// BranchPool maintains a warm set of pre-created branches off a parent
+// branch so tests can acquire a ready-to-use database without paying
+// branch-creation latency inline. It is safe for concurrent use by
+// parallel tests: the free list is a buffered channel, and a background
+// goroutine refills it toward the target size as branches are acquired.
+//
+// A BranchPool has no implicit lifetime: it keeps refilling until the
+// caller explicitly calls Drain, typically once from TestMain after
+// m.Run() returns. RunTests wraps that pattern:
+//
+//	func TestMain(m *testing.M) {
+//		pool, err := neontestdb.NewBranchPool(client, 5)
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		os.Exit(neontestdb.RunTests(pool, m))
+//	}
+//
+// Draining mid-run (e.g. on an idle timeout) isn't safe: any test still
+// in flight would find the pool permanently unable to serve Acquire.
+type BranchPool struct {
+	client       Client
+	target       int
+	free         chan pooledBranch
+	refillSignal chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+type pooledBranch struct {
+	id  string
+	uri ConnectionURI
+}
+
+// NewBranchPool pre-creates size branches off client.Branch and starts
+// the background refill loop.
+func NewBranchPool(client Client, size int) (*BranchPool, error) {
+	return NewBranchPoolContext(context.Background(), client, size)
+}
+
+func NewBranchPoolContext(ctx context.Context, client Client, size int) (*BranchPool, error) {
+	pool := &BranchPool{
+		client:       client,
+		target:       size,
+		free:         make(chan pooledBranch, size),
+		refillSignal: make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		branch, err := pool.createPoolBranch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pool.free <- branch
+	}
+	go pool.refillLoop()
+	return pool, nil
+}
+
+// Acquire blocks until a pooled branch is available or ctx is done. The
+// returned release func must be called exactly once to reset the branch
+// and return it to the pool.
+func (p *BranchPool) Acquire(ctx context.Context) (ConnectionURI, func(), error) {
+	select {
+	case branch, ok := <-p.free:
+		if !ok {
+			return ConnectionURI{}, nil, fmt.Errorf("branch pool: drained")
+		}
+		p.signalRefill()
+
+		var released int32
+		release := func() {
+			if !atomic.CompareAndSwapInt32(&released, 0, 1) {
+				return
+			}
+			p.release(branch)
+		}
+		return branch.uri, release, nil
+	case <-ctx.Done():
+		return ConnectionURI{}, nil, ctx.Err()
+	case <-p.done:
+		return ConnectionURI{}, nil, fmt.Errorf("branch pool: drained")
+	}
+}
+
+// Drain stops refilling and deletes every branch currently sitting in
+// the free list via the Neon DELETE endpoint. It is safe to call more
+// than once, and is meant to be called exactly once, after every test
+// that might Acquire has finished (see the BranchPool doc comment).
+func (p *BranchPool) Drain(ctx context.Context) error {
+	p.closeOnce.Do(func() { close(p.done) })
+
+	var errs []error
+	for {
+		select {
+		case branch := <-p.free:
+			if err := p.client.DeleteBranchContext(ctx, branch.id); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// RunTests runs m.Run(), draining pool afterwards regardless of the
+// test result, and returns the exit code for TestMain to pass to
+// os.Exit. It exists so a package's TestMain doesn't have to remember
+// to call Drain itself, which otherwise leaks every branch left in the
+// pool's free list if a caller skips that step.
+func RunTests(pool *BranchPool, m *testing.M) int {
+	code := m.Run()
+	if err := pool.Drain(context.Background()); err != nil {
+		log.Printf("branch pool: error draining: %v", err)
+	}
+	return code
+}
+
+func (p *BranchPool) signalRefill() {
+	select {
+	case p.refillSignal <- struct{}{}:
+	default:
+	}
+}
+
+func (p *BranchPool) refillLoop() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.refillSignal:
+		}
+
+		for len(p.free) < p.target {
+			branch, err := p.createPoolBranch(context.Background())
+			if err != nil {
+				log.Printf("branch pool: refill failed: %v", err)
+				break
+			}
+			select {
+			case p.free <- branch:
+			case <-p.done:
+				p.client.DeleteBranchContext(context.Background(), branch.id)
+				return
+			}
+		}
+	}
+}
+
+func (p *BranchPool) createPoolBranch(ctx context.Context) (pooledBranch, error) {
+	name := "pool-" + randomSuffix(8)
+	created, err := p.client.CreateBranchContext(ctx, name)
+	if err != nil {
+		return pooledBranch{}, fmt.Errorf("branch pool: creating %s: %w", name, err)
+	}
+	return pooledBranch{id: created.Branch.ID, uri: poolerConnectionURI(created)}, nil
+}
+
+// release resets a branch's user data and returns it to the free list,
+// or deletes it outright if the reset failed or the pool is draining.
+func (p *BranchPool) release(branch pooledBranch) {
+	ctx := context.Background()
+
+	select {
+	case <-p.done:
+		p.client.DeleteBranchContext(ctx, branch.id)
+		return
+	default:
+	}
+
+	if err := p.resetBranch(ctx, branch); err != nil {
+		log.Printf("branch pool: resetting %s failed, discarding: %v", branch.id, err)
+		p.client.DeleteBranchContext(ctx, branch.id)
+		p.signalRefill()
+		return
+	}
+
+	select {
+	case p.free <- branch:
+	default:
+		p.client.DeleteBranchContext(ctx, branch.id)
+	}
+}
+
+// resetBranch truncates every table in the public schema so the next
+// acquirer sees an empty database without paying for a fresh branch.
+func (p *BranchPool) resetBranch(ctx context.Context, branch pooledBranch) error {
+	conn, err := pgx.Connect(ctx, branch.uri.ConnectionURI)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, `select tablename from pg_tables where schemaname = 'public'`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, len(tables))
+	for i, name := range tables {
+		quoted[i] = `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	_, err = conn.Exec(ctx, fmt.Sprintf(`truncate table %s restart identity cascade`, strings.Join(quoted, ", ")))
+	return err
+}
+
+func randomSuffix(n int) string {
+	buf := make([]byte, n)
+	if _, err := cryptorand.Read(buf); err != nil {
+		rand.Read(buf)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// UsingPooledBranch acquires a branch from pool instead of calling
+// ForcedCreateBranch, and registers release via t.Cleanup so it runs
+// even after t.Fatal, t.Skip, or a panic in do.
+func (n Client) UsingPooledBranch(pool *BranchPool, t *testing.T, do func(ConnectionURI)) {
+	n.UsingPooledBranchContext(t.Context(), pool, t, do)
+}
+
+func (n Client) UsingPooledBranchContext(ctx context.Context, pool *BranchPool, t *testing.T, do func(ConnectionURI)) {
+	uri, release, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("error acquiring pooled branch: %v", err)
+	}
+	t.Cleanup(release)
+	do(uri)
 }
 
 func (n Client) BranchURL(branch string) string {
 	return fmt.Sprintf("https://console.neon.tech/api/v2/projects/%s/branches/%s", n.ProjectID, branch)
 }
 
-func (n Client) NewRequest(method, url string, body io.Reader) *http.Request {
-	req, err := http.NewRequest(method, url, body)
+func (n Client) NewRequest(method, url string, body io.Reader) (*http.Request, error) {
+	return n.NewRequestContext(context.Background(), method, url, body)
+}
+
+func (n Client) NewRequestContext(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		log.Fatalf("error creating request at %s: %v", url, err)
+		return nil, fmt.Errorf("error creating request at %s: %w", url, err)
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Authorization", "Bearer "+n.Key)
-	return req.WithContext(context.Background())
+	return req, nil
 }
 
-func (n Client) Do(req *http.Request) *http.Response {
-	resp, err := n.Client.Do(req)
-	if err != nil {
-		log.Fatalf("%s: http.Client error %v", getCallerName(1), err)
+func (n Client) Do(req *http.Request) (*http.Response, error) {
+	policy := n.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("%s: rewinding request body for retry: %w", getCallerName(1), err)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := n.Client.Do(attemptReq)
+		retry, sleep := policy.Decide(attempt, time.Since(start), resp, err)
+		if !retry {
+			if err != nil {
+				return nil, fmt.Errorf("%s: http.Client error: %w", getCallerName(1), err)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("%s: %w", getCallerName(1), req.Context().Err())
+		case <-time.After(sleep):
+		}
 	}
-	return resp
 }
 
-func parseResponse[T any](resp *http.Response) (result *T) {
-	result = new(T)
+func parseResponse[T any](resp *http.Response) (*T, error) {
+	result := new(T)
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		logFatalResponse(resp, "error decoding %T", *result)
+		return nil, newResponseError(resp, "error decoding %T", *result)
 	}
-	return result
+	return result, nil
 }
 
-func validateStatus(resp *http.Response, acceptedStatus ...int) {
+func validateStatus(resp *http.Response, acceptedStatus ...int) error {
 	if !slices.Contains(acceptedStatus, resp.StatusCode) {
-		logFatalResponse(resp, "unexpected status code Status: %d", resp.StatusCode)
+		return newResponseError(resp, "unexpected status code %d", resp.StatusCode)
 	}
+	return nil
 }
 
-func logFatalResponse(resp *http.Response, format string, a ...any) {
+// newResponseError builds a NeonAPIError from a non-matching or
+// undecodable response, capturing enough detail (status, URL, request
+// ID and body) to diagnose the failure without re-issuing the request.
+func newResponseError(resp *http.Response, format string, a ...any) *NeonAPIError {
 	bodyBytes, _ := io.ReadAll(resp.Body)
-	body := string(bodyBytes)
-	msg := fmt.Sprintf(format, a...)
-	log.Fatalf("%s: %s Url: %s Body: %s", getCallerName(2), msg, resp.Request.RequestURI, body)
+	url := ""
+	if resp.Request != nil {
+		url = resp.Request.URL.String()
+	}
+	return &NeonAPIError{
+		StatusCode: resp.StatusCode,
+		URL:        url,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		Body:       string(bodyBytes),
+		Message:    fmt.Sprintf(format, a...),
+	}
 }
 
 func getCallerName(n int) string {
@@ -205,18 +994,49 @@ func getCallerName(n int) string {
 	return runtime.FuncForPC(pc).Name()
 }
 
+// NeonAPIError reports a Neon API request that failed or returned an
+// unexpected status, so callers can inspect the status code, the
+// offending URL, the API's request ID (when present) and the decoded
+// response body instead of only seeing a formatted string.
+type NeonAPIError struct {
+	StatusCode int
+	URL        string
+	RequestID  string
+	Body       string
+	Message    string
+}
+
+func (e *NeonAPIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s: status %d url %s request-id %s body: %s", e.Message, e.StatusCode, e.URL, e.RequestID, e.Body)
+	}
+	return fmt.Sprintf("%s: status %d url %s body: %s", e.Message, e.StatusCode, e.URL, e.Body)
+}
+
 type CreateBranchRequest struct {
 	Endpoints []CreateEndpoint `json:"endpoints"`
 	Branch    CreateBranch     `json:"branch"`
 }
 
 type CreateEndpoint struct {
-	Type string `json:"type"`
+	Type                  string  `json:"type,omitempty"`
+	AutoscalingLimitMinCu float64 `json:"autoscaling_limit_min_cu,omitempty"`
+	AutoscalingLimitMaxCu float64 `json:"autoscaling_limit_max_cu,omitempty"`
+	RegionID              string  `json:"region_id,omitempty"`
+	PoolerEnabled         bool    `json:"pooler_enabled,omitempty"`
+	PoolerMode            string  `json:"pooler_mode,omitempty"`
+	SuspendTimeoutSeconds int     `json:"suspend_timeout_seconds,omitempty"`
+	Provisioner           string  `json:"provisioner,omitempty"`
 }
 
 type CreateBranch struct {
-	Name     string `json:"name"`
-	ParentID string `json:"parent_id"`
+	Name      string `json:"name"`
+	ParentID  string `json:"parent_id"`
+	ParentLSN string `json:"parent_lsn,omitempty"`
+	// ParentTimestamp must be a pointer: encoding/json's omitempty is a
+	// no-op on struct types, so a plain time.Time would marshal a zero
+	// value as "0001-01-01T00:00:00Z" on every ordinary branch create.
+	ParentTimestamp *time.Time `json:"parent_timestamp,omitempty"`
 }
 
 type Branch struct {
@@ -328,3 +1148,12 @@ type Branches struct {
 	Branches    []Branch               `json:"branches"`
 	Annotations map[string]interface{} `json:"annotations"`
 }
+
+type BranchDeleted struct {
+	Branch     Branch      `json:"branch"`
+	Operations []Operation `json:"operations"`
+}
+
+type OperationResponse struct {
+	Operation Operation `json:"operation"`
+}